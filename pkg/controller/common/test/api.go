@@ -7,7 +7,11 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/restmapper"
 	clienttesting "k8s.io/client-go/testing"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -43,6 +47,8 @@ type ControllerTestEvent struct {
 	// Name of test event, e.g. bootstrap-clean-install-no-errors.  This will seed the test name for the event.
 	Name string
 	// Execute is a function that triggers some event, e.g. mgr.GetClient().Create(someTestResource).
+	// Wrap it with WithRetry to retry on a transient error, e.g. a resource
+	// version conflict raised by the EnhancedTracker.
 	Execute GenerateEventFunc
 	// Verifier is an ActionVerifier that verifies a specific response from the system, e.g.
 	// verify that a status update occurred.  ActionVerifiers (list) can be used to ensure
@@ -56,6 +62,8 @@ type ControllerTestEvent struct {
 	Reactors []clienttesting.Reactor
 	// Timeout is the maximum amount of time to wait for the Verifier to be triggered.
 	Timeout time.Duration
+
+	t *testing.T
 }
 
 // GenerateEventFunc is a function which triggers some test action.
@@ -90,17 +98,24 @@ type ActionAssertions []ActionAssertion
 
 // AbstractActionFilter serves as a base for building ActionAssertion and
 // ActionVerifier types that filter actions based on verb, resource,
-// subresource, namespace, and name.
+// subresource, namespace, name, a label selector (for List/Watch actions, or
+// the object metadata of Create/Update/Patch actions) and a field selector
+// (for List/Watch actions only).
 type AbstractActionFilter struct {
-	Namespace   string
-	Name        string
-	Verb        string
-	Resource    string
-	Subresource string
+	Namespace     string
+	Name          string
+	Verb          string
+	Resource      string
+	Subresource   string
+	LabelSelector labels.Selector
+	FieldSelector fields.Selector
+	PatchType     types.PatchType
+	FieldManager  string
 }
 
 // Handles returns true if the action matches the settings for this verifier
-// (verb, resource, subresource, namespace, and name) and the verifier has not
+// (verb, resource, subresource, namespace, name, and label/field selector).
+// The filter keeps no state of its own, so it does not track whether it has
 // already been applied.
 func (a *AbstractActionFilter) Handles(action clienttesting.Action) bool {
 	if (action.Matches(a.Verb, a.Resource) ||
@@ -111,16 +126,28 @@ func (a *AbstractActionFilter) Handles(action clienttesting.Action) bool {
 		switch typedAction := action.(type) {
 		case clienttesting.CreateAction:
 			accessor, err := meta.Accessor(typedAction.GetObject())
-			return a.Name == "*" || (err == nil && a.Name == accessor.GetName())
+			return (a.Name == "*" || (err == nil && a.Name == accessor.GetName())) &&
+				a.matchesLabels(accessor)
 		case clienttesting.UpdateAction:
 			accessor, err := meta.Accessor(typedAction.GetObject())
-			return a.Name == "*" || (err == nil && a.Name == accessor.GetName())
+			return (a.Name == "*" || (err == nil && a.Name == accessor.GetName())) &&
+				a.matchesLabels(accessor)
+		case clienttesting.PatchAction:
+			// Checked before DeleteAction and GetAction: a PatchAction also
+			// has GetName and would otherwise be matched on its name alone.
+			return a.handlesPatch(typedAction)
 		case clienttesting.DeleteAction:
 			return a.Name == "*" || a.Name == typedAction.GetName()
 		case clienttesting.GetAction:
 			return a.Name == "*" || a.Name == typedAction.GetName()
 		case clienttesting.ListAction:
-			return true
+			restrictions := typedAction.GetListRestrictions()
+			return a.matchesSelectors(restrictions.Labels, restrictions.Fields)
+		case clienttesting.WatchAction:
+			restrictions := typedAction.GetWatchRestrictions()
+			return a.matchesSelectors(restrictions.Labels, restrictions.Fields)
+		case clienttesting.PatchAction:
+			return a.handlesPatch(typedAction)
 		case clienttesting.GenericAction:
 			return true
 		default:
@@ -130,31 +157,72 @@ func (a *AbstractActionFilter) Handles(action clienttesting.Action) bool {
 	return false
 }
 
+// matchesLabels returns true if this filter has no LabelSelector, or
+// accessor's labels satisfy it.
+func (a *AbstractActionFilter) matchesLabels(accessor metav1.Object) bool {
+	if a.LabelSelector == nil {
+		return true
+	}
+	if accessor == nil {
+		return false
+	}
+	return a.LabelSelector.Matches(labels.Set(accessor.GetLabels()))
+}
+
+// matchesSelectors returns true if this filter's LabelSelector and
+// FieldSelector (when set) match the selector a List or Watch action was
+// actually restricted to, i.e. the controller under test issued a List or
+// Watch scoped to exactly this filter's selector.
+func (a *AbstractActionFilter) matchesSelectors(actualLabels labels.Selector, actualFields fields.Selector) bool {
+	if a.LabelSelector != nil && (actualLabels == nil || actualLabels.String() != a.LabelSelector.String()) {
+		return false
+	}
+	if a.FieldSelector != nil && (actualFields == nil || actualFields.String() != a.FieldSelector.String()) {
+		return false
+	}
+	return true
+}
+
+// Matching sets the label selector that Create/Update actions (matched
+// against the object's metadata) and List/Watch actions (matched against
+// their requested restrictions) must satisfy.
+func (a *AbstractActionFilter) Matching(selector labels.Selector) *AbstractActionFilter {
+	a.LabelSelector = selector
+	return a
+}
+
+// WithFields sets the field selector that List/Watch actions must have been
+// restricted to.
+func (a *AbstractActionFilter) WithFields(selector fields.Selector) *AbstractActionFilter {
+	a.FieldSelector = selector
+	return a
+}
+
 // On initializes the resource and subresource name to which the created
 // filter should apply.  resource parameter should be specified using a slash
 // between resource an subresource, e.g. deployments/status.  Use "*" to match
 // all resources.
 func (a *AbstractActionFilter) On(resource string) *AbstractActionFilter {
-    resourceAndSub := strings.SplitN(resource, "/", 2)
-    a.Resource = resourceAndSub[0]
-    if len(resourceAndSub) > 1 {
-        a.Subresource = resourceAndSub[1]
-    }
-    return a
+	resourceAndSub := strings.SplitN(resource, "/", 2)
+	a.Resource = resourceAndSub[0]
+	if len(resourceAndSub) > 1 {
+		a.Subresource = resourceAndSub[1]
+	}
+	return a
 }
 
 // In initializes the namespace whithin which the filter should apply.  Use "*"
 // to match all namespaces.
 func (a *AbstractActionFilter) In(namespace string) *AbstractActionFilter {
-    a.Namespace = namespace
-    return a
+	a.Namespace = namespace
+	return a
 }
 
 // Named initializes the name of the resource to which the filter should apply.
 // Use "*" to match all names.
 func (a *AbstractActionFilter) Named(name string) *AbstractActionFilter {
-    a.Name = name
-    return a
+	a.Name = name
+	return a
 }
 
 // FilterString returns a sensible string for the filter, e.g. create deployments named namespace-a/some-name
@@ -163,4 +231,4 @@ func (a *AbstractActionFilter) String() string {
 		return fmt.Sprintf("%s on %s named %s in %s", a.Verb, a.Resource, a.Name, a.Namespace)
 	}
 	return fmt.Sprintf("%s on %s/%s named %s in %s", a.Verb, a.Resource, a.Subresource, a.Name, a.Namespace)
-}
\ No newline at end of file
+}
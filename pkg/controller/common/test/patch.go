@@ -0,0 +1,185 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// Patched initializes the name of the resource a patch filter should apply
+// to and sets Verb to "patch".  Use "*" to match any name.
+func (a *AbstractActionFilter) Patched(name string) *AbstractActionFilter {
+	a.Verb = "patch"
+	a.Name = name
+	return a
+}
+
+// WithFieldManager restricts this filter to patches submitted (e.g. via
+// server-side apply) with the given field manager.  Use "" (the default) to
+// match any field manager.
+func (a *AbstractActionFilter) WithFieldManager(name string) *AbstractActionFilter {
+	a.FieldManager = name
+	return a
+}
+
+// handlesPatch applies the PatchType, FieldManager, and LabelSelector
+// settings of this filter to a PatchAction, in addition to the
+// verb/resource/namespace/name matching already applied by Handles.
+// LabelSelector is checked against metadata decoded from the patch's own
+// bytes, which works for JSON Merge, Strategic Merge, and Apply patches
+// (each of which carries a JSON document for the acted-upon object); a JSON
+// Patch (RFC 6902), being a list of operations rather than a document, has
+// no metadata to decode and is exempted, matching any LabelSelector.
+func (a *AbstractActionFilter) handlesPatch(action clienttesting.PatchAction) bool {
+	if a.Name != "*" && a.Name != action.GetName() {
+		return false
+	}
+	if a.PatchType != "" && a.PatchType != action.GetPatchType() {
+		return false
+	}
+	if a.FieldManager != "" && a.FieldManager != action.GetFieldManager() {
+		return false
+	}
+	if a.LabelSelector != nil && action.GetPatchType() != types.JSONPatchType {
+		patched := &unstructured.Unstructured{}
+		if err := json.Unmarshal(action.GetPatch(), &patched.Object); err != nil {
+			return false
+		}
+		if !a.matchesLabels(patched) {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodePatch unmarshals the raw bytes of a PatchAction onto target, a
+// pointer to a zero-valued prototype of the patched type, so callers can
+// inspect the patched object's content rather than the raw patch bytes.
+// This works for JSON Merge, Strategic Merge, and Apply (server-side apply)
+// patches, all of which are encoded as a JSON document describing the
+// (partial, for Merge/Strategic Merge) desired object.  JSON Patch
+// (RFC 6902) is a list of operations rather than a document and cannot be
+// decoded this way; DecodePatch returns an error for types.JSONPatchType,
+// and callers needing to inspect one should work from the raw bytes a
+// PatchPredicate is also handed.
+func DecodePatch(patchType types.PatchType, patch []byte, target runtime.Object) error {
+	switch patchType {
+	case types.MergePatchType, types.StrategicMergePatchType, types.ApplyPatchType:
+		if u, ok := target.(*unstructured.Unstructured); ok {
+			// Unstructured's own UnmarshalJSON rejects a document without a
+			// kind, which a Merge or Strategic Merge patch usually omits.
+			return json.Unmarshal(patch, &u.Object)
+		}
+		return json.Unmarshal(patch, target)
+	default:
+		return fmt.Errorf("cannot decode patch of type %q onto a typed object", patchType)
+	}
+}
+
+// PatchPredicate inspects a patch action once its bytes have been decoded
+// onto patched (a fresh copy of the prototype object passed to
+// NewPatchVerifier), returning an error if the patch does not look the way
+// the test expects.  For a JSON Patch nothing is decoded and patched is nil;
+// only raw is available.
+type PatchPredicate func(patched runtime.Object, raw []byte) error
+
+// PatchVerifier is an ActionVerifier that fires on the first PatchAction
+// matching its filter and records whether its decoded content satisfied a
+// PatchPredicate; Verify reports a predicate failure.
+type PatchVerifier struct {
+	AbstractActionFilter
+	prototype runtime.Object
+	predicate PatchPredicate
+
+	mu     sync.Mutex
+	fired  bool
+	failed error
+	done   chan struct{}
+	t      *testing.T
+}
+
+// NewPatchVerifier returns a PatchVerifier that matches PatchActions against
+// filter, decodes their patch bytes onto a fresh copy of prototype
+// (according to the action's PatchType), and hands the result to predicate.
+func NewPatchVerifier(filter AbstractActionFilter, prototype runtime.Object, predicate PatchPredicate) *PatchVerifier {
+	return &PatchVerifier{
+		AbstractActionFilter: filter,
+		prototype:            prototype,
+		predicate:            predicate,
+		done:                 make(chan struct{}),
+	}
+}
+
+// Handles returns true if action is a PatchAction matching this verifier's
+// filter.
+func (v *PatchVerifier) Handles(action clienttesting.Action) bool {
+	patchAction, ok := action.(clienttesting.PatchAction)
+	return ok && v.AbstractActionFilter.Handles(action) && v.handlesPatch(patchAction)
+}
+
+// React decodes the patch and invokes the predicate, recording failure and
+// firing regardless of the predicate's verdict so Wait unblocks on the
+// first matching patch.
+func (v *PatchVerifier) React(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	patchAction := action.(clienttesting.PatchAction)
+	var patched runtime.Object
+	var failed error
+	if patchAction.GetPatchType() != types.JSONPatchType {
+		patched = v.prototype.DeepCopyObject()
+		failed = DecodePatch(patchAction.GetPatchType(), patchAction.GetPatch(), patched)
+	}
+	if failed == nil {
+		failed = v.predicate(patched, patchAction.GetPatch())
+	}
+
+	v.mu.Lock()
+	if !v.fired {
+		v.fired = true
+		v.failed = failed
+		close(v.done)
+	}
+	v.mu.Unlock()
+
+	return false, nil, nil
+}
+
+// Wait blocks until a matching patch has been seen or timeout elapses.
+func (v *PatchVerifier) Wait(timeout time.Duration) (timedout bool) {
+	select {
+	case <-v.done:
+		return false
+	case <-time.After(timeout):
+		return true
+	}
+}
+
+// HasFired returns true once a matching patch has been seen.
+func (v *PatchVerifier) HasFired() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.fired
+}
+
+// InjectTestRunner stores t.  The stored t is not used to report predicate
+// failures; Verify reports them through the t passed to it.
+func (v *PatchVerifier) InjectTestRunner(t *testing.T) {
+	v.t = t
+}
+
+// Verify fails t (via t.Error) if the verifier fired on a patch whose
+// content did not satisfy its PatchPredicate.  Call this after Wait returns
+// to surface predicate failures, e.g. defer patchVerifier.Verify(t).
+func (v *PatchVerifier) Verify(t *testing.T) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.fired && v.failed != nil {
+		t.Errorf("patch matching [%s] failed verification: %v", v.String(), v.failed)
+	}
+}
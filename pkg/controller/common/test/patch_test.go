@@ -0,0 +1,75 @@
+package test
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestDecodePatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType types.PatchType
+		patch     []byte
+		wantErr   bool
+	}{
+		{"merge", types.MergePatchType, []byte(`{"spec":{"replicas":3}}`), false},
+		{"strategicMerge", types.StrategicMergePatchType, []byte(`{"spec":{"replicas":3}}`), false},
+		{"apply", types.ApplyPatchType, []byte(`{"spec":{"replicas":3}}`), false},
+		{"jsonPatch", types.JSONPatchType, []byte(`[{"op":"replace","path":"/spec/replicas","value":3}]`), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			err := DecodePatch(tt.patchType, tt.patch, target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAbstractActionFilterHandlesPatch(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	mergeAction := clienttesting.NewPatchAction(gvr, "istio-system", "cm-name", types.MergePatchType, []byte(`{}`))
+	applyAction := clienttesting.NewPatchAction(gvr, "istio-system", "cm-name", types.ApplyPatchType, []byte(`{}`))
+
+	filter := &AbstractActionFilter{}
+	filter.On("configmaps").In("istio-system").Patched("cm-name")
+	filter.PatchType = types.MergePatchType
+
+	if !filter.Handles(mergeAction) {
+		t.Errorf("expected filter to handle a %s patch", types.MergePatchType)
+	}
+	if filter.Handles(applyAction) {
+		t.Errorf("expected filter to reject a %s patch when PatchType is %s", types.ApplyPatchType, types.MergePatchType)
+	}
+}
+
+func TestAbstractActionFilterHandlesPatchLabelSelector(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	matching := clienttesting.NewPatchAction(gvr, "istio-system", "cm-name", types.MergePatchType,
+		[]byte(`{"metadata":{"labels":{"app":"istiod"}}}`))
+	nonMatching := clienttesting.NewPatchAction(gvr, "istio-system", "cm-name", types.MergePatchType,
+		[]byte(`{"metadata":{"labels":{"app":"other"}}}`))
+	jsonPatch := clienttesting.NewPatchAction(gvr, "istio-system", "cm-name", types.JSONPatchType,
+		[]byte(`[{"op":"replace","path":"/spec/replicas","value":3}]`))
+
+	filter := &AbstractActionFilter{}
+	filter.On("configmaps").In("istio-system").Patched("cm-name")
+	filter.Matching(labels.SelectorFromSet(labels.Set{"app": "istiod"}))
+
+	if !filter.Handles(matching) {
+		t.Errorf("expected filter to handle a merge patch whose metadata matches the LabelSelector")
+	}
+	if filter.Handles(nonMatching) {
+		t.Errorf("expected filter to reject a merge patch whose metadata does not match the LabelSelector")
+	}
+	if !filter.Handles(jsonPatch) {
+		t.Errorf("expected filter to exempt a JSON Patch from LabelSelector matching")
+	}
+}
@@ -0,0 +1,265 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// RecordedAction is a single action captured by a Recorder, serialized in a
+// form stable enough to check in as a golden file: verb, GVR, subresource,
+// namespace/name, the acted-upon object, and an offset (rather than a wall
+// clock timestamp) from when recording started.
+type RecordedAction struct {
+	Verb         string          `json:"verb"`
+	Group        string          `json:"group,omitempty"`
+	Version      string          `json:"version,omitempty"`
+	Resource     string          `json:"resource"`
+	Subresource  string          `json:"subresource,omitempty"`
+	Namespace    string          `json:"namespace,omitempty"`
+	Name         string          `json:"name,omitempty"`
+	Object       json.RawMessage `json:"object,omitempty"`
+	OffsetMillis int64           `json:"offsetMillis"`
+}
+
+// Recorder is a clienttesting.Reactor that captures every action flowing
+// through the EnhancedTracker during a ControllerTestCase run, so the
+// resulting transcript can be checked in as a golden file and compared
+// against future runs by a Replayer.  Recorder never handles an action
+// itself (React always reports handled as false), so it can be added
+// alongside a test's other Reactors without changing behavior.
+type Recorder struct {
+	mu      sync.Mutex
+	start   time.Time
+	actions []RecordedAction
+	redact  []string
+}
+
+// NewRecorder returns a Recorder that redacts the given dotted JSON paths
+// (e.g. "metadata.resourceVersion", "metadata.generateName") out of every
+// recorded object, so that fields which vary run-to-run don't show up as
+// spurious diffs against a golden file.
+func NewRecorder(redactPaths ...string) *Recorder {
+	return &Recorder{start: time.Now(), redact: redactPaths}
+}
+
+// Handles always returns true, so that React is invoked for every action;
+// React itself never claims to handle (and thereby short-circuit) any of them.
+func (r *Recorder) Handles(action clienttesting.Action) bool {
+	return true
+}
+
+// React appends a RecordedAction describing action and returns handled as
+// false so the next reactor in the chain still runs.  The returned error is
+// nil unless the action's object could not be marshaled to JSON.
+func (r *Recorder) React(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	recorded := RecordedAction{
+		Verb:         action.GetVerb(),
+		Group:        action.GetResource().Group,
+		Version:      action.GetResource().Version,
+		Resource:     action.GetResource().Resource,
+		Subresource:  action.GetSubresource(),
+		Namespace:    action.GetNamespace(),
+		OffsetMillis: time.Since(r.start).Milliseconds(),
+	}
+
+	var obj interface{}
+	switch typedAction := action.(type) {
+	case clienttesting.CreateAction:
+		obj = typedAction.GetObject()
+		if accessor, err := meta.Accessor(obj); err == nil {
+			recorded.Name = accessor.GetName()
+		}
+	case clienttesting.UpdateAction:
+		obj = typedAction.GetObject()
+		if accessor, err := meta.Accessor(obj); err == nil {
+			recorded.Name = accessor.GetName()
+		}
+	case clienttesting.PatchAction:
+		recorded.Name = typedAction.GetName()
+		obj = json.RawMessage(typedAction.GetPatch())
+	case clienttesting.DeleteAction:
+		recorded.Name = typedAction.GetName()
+	case clienttesting.GetAction:
+		recorded.Name = typedAction.GetName()
+	}
+
+	if obj != nil {
+		raw, marshalErr := r.redactedJSON(obj)
+		if marshalErr != nil {
+			r.mu.Lock()
+			r.actions = append(r.actions, recorded)
+			r.mu.Unlock()
+			return false, nil, fmt.Errorf("recording %s: %w", action, marshalErr)
+		}
+		recorded.Object = raw
+	}
+
+	r.mu.Lock()
+	r.actions = append(r.actions, recorded)
+	r.mu.Unlock()
+	return false, nil, nil
+}
+
+// redactedJSON marshals obj to JSON and strips the paths passed to
+// NewRecorder.
+func (r *Recorder) redactedJSON(obj interface{}) (json.RawMessage, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.redact) == 0 {
+		return raw, nil
+	}
+	var generic map[string]interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		// Not a JSON object (e.g. a raw JSON Patch array); nothing to redact.
+		return raw, nil
+	}
+	for _, path := range r.redact {
+		removePath(generic, strings.Split(path, "."))
+	}
+	return json.Marshal(generic)
+}
+
+// Actions returns a copy of the actions recorded so far.
+func (r *Recorder) Actions() []RecordedAction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	actions := make([]RecordedAction, len(r.actions))
+	copy(actions, r.actions)
+	return actions
+}
+
+// WriteGolden serializes the recorded actions as indented JSON to path,
+// e.g. to create or update a checked-in golden file.
+func (r *Recorder) WriteGolden(path string) error {
+	raw, err := json.MarshalIndent(r.Actions(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recorded actions: %w", err)
+	}
+	raw = append(raw, '\n')
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write golden file %s: %w", path, err)
+	}
+	return nil
+}
+
+// removePath deletes the field named by path (a dotted JSON path already
+// split on ".") from doc, descending into nested objects as needed.
+func removePath(doc map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	if len(path) == 1 {
+		delete(doc, path[0])
+		return
+	}
+	if child, ok := doc[path[0]].(map[string]interface{}); ok {
+		removePath(child, path[1:])
+	}
+}
+
+// Replayer diffs a Recorder's actions against a checked-in golden file,
+// ignoring configured paths (e.g. "metadata.resourceVersion" or generated
+// names) that are expected to vary between runs. This gives operator
+// authors a low-effort regression harness: a change to SMCP rendering
+// surfaces as a reviewable diff of which actions the controller emitted, in
+// what order, with what payloads, rather than requiring a hand-written
+// assertion for every rendered resource.
+type Replayer struct {
+	ignore []string
+}
+
+// NewReplayer returns a Replayer that ignores the given dotted JSON paths
+// when comparing recorded objects.
+func NewReplayer(ignorePaths ...string) *Replayer {
+	return &Replayer{ignore: ignorePaths}
+}
+
+// Diff compares actual against the golden file at goldenPath and returns a
+// human-readable description of any differences, or "" if they match.
+func (p *Replayer) Diff(goldenPath string, actual *Recorder) (string, error) {
+	goldenRaw, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read golden file %s: %w", goldenPath, err)
+	}
+	var golden []RecordedAction
+	if err := json.Unmarshal(goldenRaw, &golden); err != nil {
+		return "", fmt.Errorf("failed to parse golden file %s: %w", goldenPath, err)
+	}
+
+	got := actual.Actions()
+	golden = p.normalize(golden)
+	got = p.normalize(got)
+
+	if len(golden) != len(got) {
+		return fmt.Sprintf("expected %d actions, got %d:\nwant: %s\ngot:  %s", len(golden), len(got), summarize(golden), summarize(got)), nil
+	}
+	for i := range golden {
+		if !reflect.DeepEqual(golden[i], got[i]) {
+			return fmt.Sprintf("action %d differs:\nwant: %s\ngot:  %s", i, mustJSON(golden[i]), mustJSON(got[i])), nil
+		}
+	}
+	return "", nil
+}
+
+// AssertMatches fails t if actual does not match the golden file at
+// goldenPath.
+func (p *Replayer) AssertMatches(t *testing.T, goldenPath string, actual *Recorder) {
+	diff, err := p.Diff(goldenPath, actual)
+	if err != nil {
+		t.Fatalf("failed to diff against golden file: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("recorded actions do not match %s:\n%s", goldenPath, diff)
+	}
+}
+
+// normalize returns a copy of actions with OffsetMillis zeroed (timing is
+// never compared) and the Replayer's ignore paths stripped from each
+// recorded object.
+func (p *Replayer) normalize(actions []RecordedAction) []RecordedAction {
+	normalized := make([]RecordedAction, len(actions))
+	for i, action := range actions {
+		action.OffsetMillis = 0
+		if len(p.ignore) > 0 && len(action.Object) > 0 {
+			var generic map[string]interface{}
+			if err := json.Unmarshal(action.Object, &generic); err == nil {
+				for _, path := range p.ignore {
+					removePath(generic, strings.Split(path, "."))
+				}
+				if raw, err := json.Marshal(generic); err == nil {
+					action.Object = raw
+				}
+			}
+		}
+		normalized[i] = action
+	}
+	return normalized
+}
+
+func summarize(actions []RecordedAction) string {
+	parts := make([]string, len(actions))
+	for i, action := range actions {
+		parts[i] = fmt.Sprintf("%s %s/%s %s/%s", action.Verb, action.Resource, action.Subresource, action.Namespace, action.Name)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func mustJSON(action RecordedAction) string {
+	raw, err := json.Marshal(action)
+	if err != nil {
+		return fmt.Sprintf("<unmarshalable: %v>", err)
+	}
+	return string(raw)
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// TestRecorderCapturesNameOnCreateAndUpdate guards against regressing to a
+// type switch that only populates RecordedAction.Name for Patch/Delete/Get,
+// leaving the two most common reconciler actions unnamed in a golden file.
+func TestRecorderCapturesNameOnCreateAndUpdate(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "istio-installer-version"},
+	}}
+
+	tests := []struct {
+		name   string
+		action clienttesting.Action
+	}{
+		{"create", clienttesting.NewCreateAction(gvr, "istio-system", obj)},
+		{"update", clienttesting.NewUpdateAction(gvr, "istio-system", obj)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRecorder()
+			if _, _, err := r.React(tt.action); err != nil {
+				t.Fatalf("React returned error: %v", err)
+			}
+			actions := r.Actions()
+			if len(actions) != 1 {
+				t.Fatalf("expected 1 recorded action, got %d", len(actions))
+			}
+			if actions[0].Name != "istio-installer-version" {
+				t.Errorf("expected Name to be populated, got %q", actions[0].Name)
+			}
+		})
+	}
+}
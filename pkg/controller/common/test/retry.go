@@ -0,0 +1,103 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// RetryPolicy describes how a ControllerTestEvent's Execute step should be
+// retried when it fails with a transient error, e.g. a resource version
+// conflict raised by the EnhancedTracker.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times Execute will be attempted.
+	// A value <= 1 disables retrying.
+	MaxAttempts int
+	// InitialDelay is the delay before the second attempt.  Subsequent
+	// delays are scaled by BackoffFactor.
+	InitialDelay time.Duration
+	// BackoffFactor scales InitialDelay on each subsequent retry, e.g. 2.0
+	// doubles the delay every attempt.  A value <= 0 is treated as 1 (no
+	// growth).
+	BackoffFactor float64
+	// Retryable reports whether err should trigger another attempt.  If nil,
+	// only conflict errors (apierrors.IsConflict) are retried, which covers
+	// the common case of a stale resourceVersion on the EnhancedTracker.
+	Retryable func(err error) bool
+}
+
+// attempts returns the policy's MaxAttempts, treating a zero value as 1
+// (i.e. no retrying) so the zero-value RetryPolicy is a no-op.
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// delayFor returns the delay that should be observed before the given retry
+// attempt (attempt is 1 for the first retry, i.e. the second overall try).
+func (p RetryPolicy) delayFor(attempt int) time.Duration {
+	factor := p.BackoffFactor
+	if factor <= 0 {
+		factor = 1
+	}
+	delay := float64(p.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= factor
+	}
+	return time.Duration(delay)
+}
+
+// retryable reports whether err should cause another attempt under this
+// policy, falling back to apierrors.IsConflict when Retryable is unset.
+func (p RetryPolicy) retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return apierrors.IsConflict(err)
+}
+
+// WithRetry wraps e.Execute so that it is retried under policy whenever it
+// returns a transient error, and returns e for chaining, e.g.
+// event.WithRetry(RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond * 10, BackoffFactor: 2}).
+// e.Execute must already be set; WithRetry wraps whatever is there at the
+// time it is called, so call it after assigning Execute.  Call
+// InjectTestRunner beforehand to have each retry logged through t.Log.
+func (e *ControllerTestEvent) WithRetry(policy RetryPolicy) *ControllerTestEvent {
+	inner := e.Execute
+	e.Execute = func(mgr *FakeManager, tracker *EnhancedTracker) error {
+		var err error
+		for attempt := 1; attempt <= policy.attempts(); attempt++ {
+			if err = inner(mgr, tracker); err == nil {
+				return nil
+			}
+			if !policy.retryable(err) {
+				return err
+			}
+			if attempt == policy.attempts() {
+				break
+			}
+			delay := policy.delayFor(attempt)
+			if e.t != nil {
+				e.t.Logf("%s: retrying execute after error (attempt %d/%d, delay %s): %v", e.Name, attempt, policy.attempts(), delay, err)
+			}
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+		}
+		return err
+	}
+	return e
+}
+
+// InjectTestRunner injects t, used by a retry wrapped onto Execute via
+// WithRetry to log each attempt through t.Log.  Analogous to
+// ActionVerifier.InjectTestRunner.
+func (e *ControllerTestEvent) InjectTestRunner(t *testing.T) {
+	e.t = t
+}
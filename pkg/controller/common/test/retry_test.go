@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWithRetryRetriesTransientErrors(t *testing.T) {
+	gr := schema.GroupResource{Group: "", Resource: "configmaps"}
+	attempts := 0
+	event := &ControllerTestEvent{
+		Name: "retry-on-conflict",
+		Execute: func(mgr *FakeManager, tracker *EnhancedTracker) error {
+			attempts++
+			if attempts < 3 {
+				return apierrors.NewConflict(gr, "cm-name", fmt.Errorf("stale resourceVersion"))
+			}
+			return nil
+		},
+	}
+	event.WithRetry(RetryPolicy{MaxAttempts: 3})
+
+	if err := event.Execute(nil, nil); err != nil {
+		t.Fatalf("Execute() error = %v, want nil after retrying", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestWithRetryStopsOnNonRetryableError(t *testing.T) {
+	attempts := 0
+	event := &ControllerTestEvent{
+		Name: "non-retryable",
+		Execute: func(mgr *FakeManager, tracker *EnhancedTracker) error {
+			attempts++
+			return fmt.Errorf("boom")
+		},
+	}
+	event.WithRetry(RetryPolicy{MaxAttempts: 3})
+
+	if err := event.Execute(nil, nil); err == nil {
+		t.Fatal("expected Execute() to return an error")
+	}
+	if attempts != 1 {
+		t.Errorf("expected a non-retryable error to stop after 1 attempt, got %d", attempts)
+	}
+}
@@ -0,0 +1,283 @@
+// Package scenario supports describing a ControllerTestCase declaratively,
+// as a YAML (or JSON) file, rather than as a hand-built Go struct.  A
+// scenario file looks roughly like:
+//
+//	resources:
+//	  - apiVersion: v1
+//	    kind: ConfigMap
+//	    metadata:
+//	      name: istio-installer-version
+//	events:
+//	  - name: create-smcp
+//	    execute:
+//	      verb: create
+//	      manifest:
+//	        apiVersion: maistra.io/v2
+//	        kind: ServiceMeshControlPlane
+//	        metadata:
+//	          name: basic
+//	          namespace: istio-system
+//	    reactors:
+//	      - on: servicemeshcontrolplanes
+//	        verb: get
+//	        error: NotFound
+//	    verify:
+//	      on: servicemeshcontrolplanes/status
+//	      named: basic
+//	      in: istio-system
+//	    assert:
+//	      - on: deployments
+//	        in: istio-system
+//	        atLeast: 1
+//	    timeout: 30s
+//
+// A file may contain several "---"-separated documents; each is merged into
+// the resulting ControllerTestCase in order, so resources and events common
+// to a suite can be factored into a shared document.
+//
+// This mirrors gdt-kube's approach of expressing Kube actions and
+// assertions as data rather than code, so reconciliation scenarios can be
+// versioned, reviewed, and shared between suites.
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/maistra/istio-operator/pkg/controller/common/test"
+)
+
+// scenarioFieldOwner is the field manager used for execute: verb: apply,
+// i.e. server-side apply, actions issued on behalf of a scenario file.
+const scenarioFieldOwner = "istio-operator-scenario"
+
+// document is the shape of a single YAML/JSON document within a scenario
+// file.
+type document struct {
+	Resources []map[string]interface{} `json:"resources"`
+	Events    []eventSpec              `json:"events"`
+}
+
+// eventSpec describes one ControllerTestEvent.
+type eventSpec struct {
+	Name     string        `json:"name"`
+	Execute  executeSpec   `json:"execute"`
+	Reactors []reactorSpec `json:"reactors"`
+	Verify   *actionSpec   `json:"verify"`
+	Assert   []assertSpec  `json:"assert"`
+	Timeout  string        `json:"timeout"`
+}
+
+// executeSpec describes the action that should be performed against the
+// FakeManager's client to trigger the event.
+type executeSpec struct {
+	Verb     string                 `json:"verb"`
+	Manifest map[string]interface{} `json:"manifest"`
+}
+
+// actionSpec describes an AbstractActionFilter in data form.  Fields mirror
+// the fluent builder methods on test.AbstractActionFilter; unset fields
+// default to "*" (match anything), except the subresource: when neither
+// subresource: nor a slash in on: gives one, only actions without a
+// subresource match.
+type actionSpec struct {
+	On          string `json:"on"`
+	In          string `json:"in"`
+	Named       string `json:"named"`
+	Verb        string `json:"verb"`
+	Subresource string `json:"subresource"`
+}
+
+// assertSpec is an actionSpec together with a simple counting assertion.
+type assertSpec struct {
+	actionSpec
+	Count   *int `json:"count"`
+	AtLeast *int `json:"atLeast"`
+	AtMost  *int `json:"atMost"`
+}
+
+// reactorSpec is an actionSpec together with the error that should be
+// returned for any matching action, e.g. to simulate a NotFound response
+// from a particular client.Get() call.
+type reactorSpec struct {
+	actionSpec
+	Error string `json:"error"`
+}
+
+// LoadFile parses the scenario file at path and materializes the
+// corresponding test.ControllerTestCase.  groupResources is used to seed the
+// RESTMapper that GVKs embedded in the file's manifests are resolved
+// against, and should generally be the same slice passed as
+// ControllerTestCase.GroupResources.
+func LoadFile(path string, groupResources []*restmapper.APIGroupResources) (test.ControllerTestCase, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return test.ControllerTestCase{}, fmt.Errorf("failed to read scenario file %s: %w", path, err)
+	}
+	return Load(raw, groupResources)
+}
+
+// Load parses raw as a (possibly multi-document) YAML/JSON scenario and
+// materializes the corresponding test.ControllerTestCase.
+func Load(raw []byte, groupResources []*restmapper.APIGroupResources) (test.ControllerTestCase, error) {
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+	tc := test.ControllerTestCase{}
+
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+	for {
+		var doc document
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return test.ControllerTestCase{}, fmt.Errorf("failed to parse scenario: %w", err)
+		}
+
+		for _, res := range doc.Resources {
+			obj, err := toUnstructured(res, mapper)
+			if err != nil {
+				return test.ControllerTestCase{}, err
+			}
+			tc.Resources = append(tc.Resources, obj)
+		}
+
+		for _, es := range doc.Events {
+			event, err := toEvent(es, mapper)
+			if err != nil {
+				return test.ControllerTestCase{}, fmt.Errorf("event %s: %w", es.Name, err)
+			}
+			tc.Events = append(tc.Events, event)
+		}
+	}
+
+	return tc, nil
+}
+
+// toEvent converts an eventSpec into a test.ControllerTestEvent, resolving
+// the execute manifest's GVK against mapper and building the Verifier and
+// Assertions from their respective specs.
+func toEvent(es eventSpec, mapper meta.RESTMapper) (test.ControllerTestEvent, error) {
+	manifest, err := toUnstructured(es.Execute.Manifest, mapper)
+	if err != nil {
+		return test.ControllerTestEvent{}, err
+	}
+	verb := es.Execute.Verb
+
+	event := test.ControllerTestEvent{
+		Name: es.Name,
+		Execute: func(mgr *test.FakeManager, tracker *test.EnhancedTracker) error {
+			return executeManifest(mgr, verb, manifest)
+		},
+	}
+
+	if es.Timeout != "" {
+		timeout, err := time.ParseDuration(es.Timeout)
+		if err != nil {
+			return test.ControllerTestEvent{}, fmt.Errorf("invalid timeout %q: %w", es.Timeout, err)
+		}
+		event.Timeout = timeout
+	}
+
+	for _, rs := range es.Reactors {
+		reactor, err := toReactor(rs)
+		if err != nil {
+			return test.ControllerTestEvent{}, fmt.Errorf("reactor on %q: %w", rs.On, err)
+		}
+		event.Reactors = append(event.Reactors, reactor)
+	}
+
+	if es.Verify != nil {
+		event.Verifier = newFilterVerifier(toFilter(*es.Verify))
+	}
+
+	for _, as := range es.Assert {
+		event.Assertions = append(event.Assertions, newCountAssertion(toFilter(as.actionSpec), as.Count, as.AtLeast, as.AtMost))
+	}
+
+	return event, nil
+}
+
+// toFilter builds a test.AbstractActionFilter from an actionSpec.
+func toFilter(spec actionSpec) test.AbstractActionFilter {
+	filter := test.AbstractActionFilter{Verb: orStar(spec.Verb)}
+	filter.On(orStar(spec.On))
+	filter.In(orStar(spec.In))
+	filter.Named(orStar(spec.Named))
+	if spec.Subresource != "" {
+		filter.Subresource = spec.Subresource
+	}
+	return filter
+}
+
+// toReactor builds a clienttesting.Reactor from a reactorSpec: an
+// AbstractActionFilter (as built by toFilter) paired with the error it
+// should return for every matching action.
+func toReactor(spec reactorSpec) (clienttesting.Reactor, error) {
+	newError, err := errorFactory(spec.Error)
+	if err != nil {
+		return nil, err
+	}
+	return newErrorReactor(toFilter(spec.actionSpec), newError), nil
+}
+
+func orStar(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
+
+// toUnstructured converts a decoded YAML/JSON map into an
+// unstructured.Unstructured, resolving and validating its GVK against
+// mapper.  A nil map (e.g. an event with no execute manifest) yields a nil
+// object.
+func toUnstructured(m map[string]interface{}, mapper meta.RESTMapper) (*unstructured.Unstructured, error) {
+	if m == nil {
+		return nil, nil
+	}
+	obj := &unstructured.Unstructured{Object: m}
+	gvk := obj.GroupVersionKind()
+	if gvk.Empty() {
+		return nil, fmt.Errorf("manifest is missing apiVersion/kind")
+	}
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		return nil, fmt.Errorf("resolving %s against seeded GroupResources: %w", gvk, err)
+	}
+	return obj, nil
+}
+
+// executeManifest performs verb (create, update, apply, or delete) against
+// obj using the FakeManager's client.  apply issues a real server-side
+// apply patch (client.Apply under scenarioFieldOwner), so it produces a
+// PatchAction observable by the PatchVerifier/Patched() machinery rather
+// than behaving like update.
+func executeManifest(mgr *test.FakeManager, verb string, obj *unstructured.Unstructured) error {
+	if obj == nil {
+		return fmt.Errorf("execute has no manifest")
+	}
+	c := mgr.GetClient()
+	ctx := context.Background()
+	switch verb {
+	case "", "create":
+		return c.Create(ctx, obj)
+	case "update":
+		return c.Update(ctx, obj)
+	case "apply":
+		return c.Patch(ctx, obj, client.Apply, client.FieldOwner(scenarioFieldOwner), client.ForceOwnership)
+	case "delete":
+		return c.Delete(ctx, obj)
+	default:
+		return fmt.Errorf("unsupported execute verb %q", verb)
+	}
+}
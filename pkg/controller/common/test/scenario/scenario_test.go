@@ -0,0 +1,52 @@
+package scenario
+
+import (
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestToFilterSubresource(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    actionSpec
+		wantSub string
+	}{
+		{"explicit subresource", actionSpec{On: "servicemeshcontrolplanes", Subresource: "status"}, "status"},
+		{"subresource parsed from on", actionSpec{On: "servicemeshcontrolplanes/status"}, "status"},
+		{"no subresource", actionSpec{On: "servicemeshcontrolplanes"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := toFilter(tt.spec)
+			if filter.Subresource != tt.wantSub {
+				t.Errorf("Subresource = %q, want %q", filter.Subresource, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestToReactorNotFound(t *testing.T) {
+	reactor, err := toReactor(reactorSpec{actionSpec: actionSpec{On: "configmaps", Verb: "get"}, Error: "NotFound"})
+	if err != nil {
+		t.Fatalf("toReactor() error = %v", err)
+	}
+
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	action := clienttesting.NewGetAction(gvr, "istio-system", "istio-installer-version")
+
+	if !reactor.Handles(action) {
+		t.Fatalf("expected reactor to handle %s", action)
+	}
+	if _, _, err := reactor.React(action); !apierrors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got %v", err)
+	}
+}
+
+func TestErrorFactoryRequiresError(t *testing.T) {
+	if _, err := errorFactory(""); err == nil {
+		t.Error("expected an error for an empty reactor error")
+	}
+}
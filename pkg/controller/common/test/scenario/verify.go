@@ -0,0 +1,140 @@
+package scenario
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/maistra/istio-operator/pkg/controller/common/test"
+)
+
+// filterVerifier is a test.ActionVerifier that fires the first time an
+// action matches its test.AbstractActionFilter, built from a scenario
+// file's verify: block.
+type filterVerifier struct {
+	test.AbstractActionFilter
+	fired chan struct{}
+	once  sync.Once
+	t     *testing.T
+}
+
+func newFilterVerifier(filter test.AbstractActionFilter) *filterVerifier {
+	return &filterVerifier{AbstractActionFilter: filter, fired: make(chan struct{})}
+}
+
+func (v *filterVerifier) React(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	v.once.Do(func() { close(v.fired) })
+	return false, nil, nil
+}
+
+func (v *filterVerifier) Wait(timeout time.Duration) (timedout bool) {
+	select {
+	case <-v.fired:
+		return false
+	case <-time.After(timeout):
+		return true
+	}
+}
+
+func (v *filterVerifier) HasFired() bool {
+	select {
+	case <-v.fired:
+		return true
+	default:
+		return false
+	}
+}
+
+func (v *filterVerifier) InjectTestRunner(t *testing.T) {
+	v.t = t
+}
+
+// errorReactor is a clienttesting.Reactor that returns a fixed error for
+// every action matching its test.AbstractActionFilter, built from a
+// scenario file's reactors: block, e.g. to simulate a NotFound response from
+// a particular client.Get() call.
+type errorReactor struct {
+	test.AbstractActionFilter
+	newError func(action clienttesting.Action) error
+}
+
+func newErrorReactor(filter test.AbstractActionFilter, newError func(action clienttesting.Action) error) *errorReactor {
+	return &errorReactor{AbstractActionFilter: filter, newError: newError}
+}
+
+func (r *errorReactor) React(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	return true, nil, r.newError(action)
+}
+
+// errorFactory returns a function that builds the error a matching
+// errorReactor should return for action.  name is matched case-sensitively
+// against a handful of well-known apierrors reasons (NotFound, Conflict,
+// AlreadyExists, Forbidden); any other, non-empty name is returned verbatim
+// as a plain error, e.g. to simulate an arbitrary webhook rejection message.
+func errorFactory(name string) (func(action clienttesting.Action) error, error) {
+	switch name {
+	case "":
+		return nil, fmt.Errorf("reactor requires an error")
+	case "NotFound":
+		return func(action clienttesting.Action) error {
+			return apierrors.NewNotFound(groupResource(action), "")
+		}, nil
+	case "Conflict":
+		return func(action clienttesting.Action) error {
+			return apierrors.NewConflict(groupResource(action), "", fmt.Errorf("conflict"))
+		}, nil
+	case "AlreadyExists":
+		return func(action clienttesting.Action) error {
+			return apierrors.NewAlreadyExists(groupResource(action), "")
+		}, nil
+	case "Forbidden":
+		return func(action clienttesting.Action) error {
+			return apierrors.NewForbidden(groupResource(action), "", fmt.Errorf("forbidden"))
+		}, nil
+	default:
+		return func(action clienttesting.Action) error {
+			return fmt.Errorf("%s", name)
+		}, nil
+	}
+}
+
+// groupResource returns the GroupResource action was issued against, for
+// building the apierrors errors above.
+func groupResource(action clienttesting.Action) schema.GroupResource {
+	return action.GetResource().GroupResource()
+}
+
+// countAssertion is a test.ActionAssertion that counts the actions matching
+// its test.AbstractActionFilter and checks the count against a scenario
+// file's assert: block (count:, atLeast:, atMost:).
+type countAssertion struct {
+	test.AbstractActionFilter
+	count, atLeast, atMost *int
+	seen                   int
+}
+
+func newCountAssertion(filter test.AbstractActionFilter, count, atLeast, atMost *int) *countAssertion {
+	return &countAssertion{AbstractActionFilter: filter, count: count, atLeast: atLeast, atMost: atMost}
+}
+
+func (a *countAssertion) React(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	a.seen++
+	return false, nil, nil
+}
+
+func (a *countAssertion) Assert(t *testing.T) {
+	switch {
+	case a.count != nil && a.seen != *a.count:
+		t.Errorf("expected exactly %d actions matching [%s], got %d", *a.count, a.String(), a.seen)
+	case a.atLeast != nil && a.seen < *a.atLeast:
+		t.Errorf("expected at least %d actions matching [%s], got %d", *a.atLeast, a.String(), a.seen)
+	case a.atMost != nil && a.seen > *a.atMost:
+		t.Errorf("expected at most %d actions matching [%s], got %d", *a.atMost, a.String(), a.seen)
+	}
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// SequenceMatcher is anything that can decide whether it matches a given
+// action and describe itself, e.g. an *AbstractActionFilter or a type built
+// on top of one.
+type SequenceMatcher interface {
+	Handles(action clienttesting.Action) bool
+	fmt.Stringer
+}
+
+// SequenceMode selects how a Sequence matches its matchers against the
+// stream of actions it observes.
+type SequenceMode int
+
+const (
+	// Strict requires matchers to match, in order, and fails the sequence
+	// the moment an action matches a matcher further down the list before
+	// the current one has matched, e.g. a status Update seen before the
+	// Create it should follow.
+	Strict SequenceMode = iota
+	// InOrder requires matchers to match in order, but tolerates actions
+	// that match a later matcher before their turn: such actions are
+	// simply ignored rather than failing the sequence.
+	InOrder
+	// AnyOrder requires every matcher to match at least once, in any
+	// order.
+	AnyOrder
+	// Eventually behaves like InOrder, but is meant to be paired with a
+	// generous Timeout (and, on the Execute side, WithRetry): it never
+	// fails outright on an out-of-turn action, only on Verify once the
+	// caller has given up waiting.
+	Eventually
+)
+
+// Sequence is an ActionVerifier that tracks an ordered (or, in AnyOrder
+// mode, unordered) list of SequenceMatchers across every action it
+// observes, rather than a single match like a plain AbstractActionFilter.
+// It is meant to be shared across a ControllerTestCase's Events, e.g. by
+// assigning the same *Sequence as the Verifier of more than one
+// ControllerTestEvent, so that a correlation spanning several events (and
+// therefore several reconciles) can be expressed as a single assertion.
+type Sequence struct {
+	Matchers []SequenceMatcher
+	Mode     SequenceMode
+
+	mu        sync.Mutex
+	next      int
+	matched   map[int]bool
+	prevMatch clienttesting.Action
+	correlate func(prev, curr clienttesting.Action) bool
+	failed    error
+	done      chan struct{}
+	closeOnce sync.Once
+	t         *testing.T
+}
+
+// NewSequence returns a Sequence that matches matchers, in the order given,
+// according to mode.
+func NewSequence(mode SequenceMode, matchers ...SequenceMatcher) *Sequence {
+	return &Sequence{
+		Matchers: matchers,
+		Mode:     mode,
+		matched:  map[int]bool{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Correlate registers fn to run against the previously matched action and
+// the action about to be accepted as the next match, e.g. to assert that
+// the Update to deployments/status for istiod references the same
+// resourceVersion seen in the immediately preceding Get.  fn should return
+// false to fail the sequence.  Correlate only applies in Strict, InOrder,
+// and Eventually modes, since AnyOrder has no notion of "preceding" match.
+func (s *Sequence) Correlate(fn func(prev, curr clienttesting.Action) bool) *Sequence {
+	s.correlate = fn
+	return s
+}
+
+// Handles returns true if action matches any matcher this sequence has not
+// yet resolved, so that React below can decide whether it is (or isn't) the
+// expected next match.
+func (s *Sequence) Handles(action clienttesting.Action) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isDoneLocked() {
+		return false
+	}
+	if s.Mode == AnyOrder {
+		for i, m := range s.Matchers {
+			if !s.matched[i] && m.Handles(action) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, m := range s.Matchers[s.next:] {
+		if m.Handles(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// React advances the sequence's progress.  It never claims to handle the
+// action (so other reactors, including the real tracker, still process it).
+func (s *Sequence) React(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isDoneLocked() {
+		return false, nil, nil
+	}
+
+	if s.Mode == AnyOrder {
+		for i, m := range s.Matchers {
+			if !s.matched[i] && m.Handles(action) {
+				s.matched[i] = true
+				break
+			}
+		}
+		if len(s.matched) == len(s.Matchers) {
+			s.finishLocked(nil)
+		}
+		return false, nil, nil
+	}
+
+	current := s.Matchers[s.next]
+	if current.Handles(action) {
+		if s.correlate != nil && s.prevMatch != nil && !s.correlate(s.prevMatch, action) {
+			s.finishLocked(fmt.Errorf("correlation failed between %s and %s matching [%s]", s.prevMatch, action, current))
+			return false, nil, nil
+		}
+		s.prevMatch = action
+		s.next++
+		if s.next == len(s.Matchers) {
+			s.finishLocked(nil)
+		}
+		return false, nil, nil
+	}
+
+	if s.Mode == Strict {
+		for i, m := range s.Matchers {
+			if i > s.next && m.Handles(action) {
+				s.finishLocked(fmt.Errorf("action %s matched [%s] (position %d) before [%s] (position %d) had matched", action, m, i, current, s.next))
+				break
+			}
+		}
+	}
+	// InOrder and Eventually: an action matching a matcher out of turn is
+	// simply ignored; only the eventual order of accepted matches counts.
+	return false, nil, nil
+}
+
+// finishLocked records err (nil on success) and signals done.  Callers must
+// hold s.mu.
+func (s *Sequence) finishLocked(err error) {
+	s.failed = err
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// isDoneLocked reports whether the sequence has already resolved (matched
+// or failed).  Callers must hold s.mu.
+func (s *Sequence) isDoneLocked() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until the sequence resolves (all matchers matched, in Strict
+// mode an ordering failure was detected, or a Correlate check failed) or
+// timeout elapses.
+func (s *Sequence) Wait(timeout time.Duration) (timedout bool) {
+	select {
+	case <-s.done:
+		return false
+	case <-time.After(timeout):
+		return true
+	}
+}
+
+// HasFired returns true once the sequence has resolved, successfully or
+// not; check Verify (or the error surfaced there) to distinguish the two.
+func (s *Sequence) HasFired() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// InjectTestRunner stores t.  The stored t is not used to report failures;
+// Verify reports them through the t passed to it.
+func (s *Sequence) InjectTestRunner(t *testing.T) {
+	s.t = t
+}
+
+// Verify fails t if the sequence resolved with an error (a Strict ordering
+// violation or a failed Correlate), or if it never resolved at all (i.e.
+// Wait timed out before every matcher matched).
+func (s *Sequence) Verify(t *testing.T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isDoneLocked() {
+		t.Errorf("sequence did not complete: %d/%d matchers matched", len(s.matched)+s.next, len(s.Matchers))
+		return
+	}
+	if s.failed != nil {
+		t.Error(s.failed)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func matcher(verb, resource string) *AbstractActionFilter {
+	f := &AbstractActionFilter{Namespace: "*", Name: "*"}
+	f.Verb = verb
+	f.On(resource)
+	return f
+}
+
+func TestSequenceInOrderIgnoresOutOfTurnActions(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	create := clienttesting.NewCreateAction(gvr, "ns", nil)
+	update := clienttesting.NewUpdateAction(gvr, "ns", nil)
+
+	seq := NewSequence(InOrder, matcher("create", "configmaps"), matcher("update", "configmaps"))
+	seq.React(update)
+	seq.React(create)
+	seq.React(update)
+
+	if timedout := seq.Wait(time.Second); timedout {
+		t.Fatal("sequence did not complete")
+	}
+	if seq.failed != nil {
+		t.Errorf("expected sequence to succeed, got %v", seq.failed)
+	}
+}
+
+func TestSequenceStrictFailsOnOutOfOrderAction(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	update := clienttesting.NewUpdateAction(gvr, "ns", nil)
+
+	seq := NewSequence(Strict, matcher("create", "configmaps"), matcher("update", "configmaps"))
+	seq.React(update)
+
+	if !seq.HasFired() {
+		t.Fatal("expected Strict mode to fail immediately on an out-of-order action")
+	}
+	if seq.failed == nil {
+		t.Error("expected a failure error to be recorded")
+	}
+}
+
+func TestSequenceAnyOrderMatchesRegardlessOfOrder(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	create := clienttesting.NewCreateAction(gvr, "ns", nil)
+	update := clienttesting.NewUpdateAction(gvr, "ns", nil)
+
+	seq := NewSequence(AnyOrder, matcher("create", "configmaps"), matcher("update", "configmaps"))
+	seq.React(update)
+	seq.React(create)
+
+	if !seq.HasFired() {
+		t.Fatal("expected AnyOrder sequence to complete regardless of order")
+	}
+	if seq.failed != nil {
+		t.Errorf("expected sequence to succeed, got %v", seq.failed)
+	}
+}
+
+func TestSequenceCorrelateFailsSequence(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}
+	create := clienttesting.NewCreateAction(gvr, "ns", nil)
+	update := clienttesting.NewUpdateAction(gvr, "ns", nil)
+
+	seq := NewSequence(InOrder, matcher("create", "configmaps"), matcher("update", "configmaps")).
+		Correlate(func(prev, curr clienttesting.Action) bool { return false })
+	seq.React(create)
+	seq.React(update)
+
+	if !seq.HasFired() {
+		t.Fatal("expected sequence to resolve once correlation fails")
+	}
+	if seq.failed == nil {
+		t.Error("expected correlation failure to be recorded")
+	}
+}